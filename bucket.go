@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"go.uber.org/zap"
+)
+
+// BucketReward is one grouped reward sum over a single time bucket,
+// e.g. one calendar day or one hour.
+type BucketReward struct {
+	BucketStart time.Time
+	Reward      float64
+}
+
+// bucketSQLTemplate is bound to $1 account, $2 project, $3 timezone, $4
+// bucket start and $5 bucket end, all as query parameters rather than
+// Sprintf'd into the query text, so a project name or timezone string
+// containing a quote can't break the query.
+const bucketSQLTemplate = `
+        SELECT COALESCE(SUM(reward)/1e6, 0)
+        FROM epoch_distributor
+        WHERE project = $2
+          AND miner_account_id IN (
+              SELECT id FROM miner_account WHERE name = $1
+          )
+          AND epoch_time AT TIME ZONE $3 >= $4
+          AND epoch_time AT TIME ZONE $3 < $5
+    `
+
+// queryRewardBucket sums an account's reward over [start, end) in job's timezone.
+func queryRewardBucket(ctx context.Context, db *sql.DB, job Job, account string, start, end time.Time) (float64, error) {
+	var reward float64
+	err := db.QueryRowContext(ctx, bucketSQLTemplate, account, job.Project, job.Timezone, start, end).Scan(&reward)
+	if err != nil {
+		return 0, err
+	}
+	return reward, nil
+}
+
+// bucketWidth returns the duration of one bucket for the given granularity.
+func bucketWidth(granularity string) time.Duration {
+	if granularity == "hour" {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// bucketWindow returns the `backfill` most recent bucket start times in loc,
+// oldest first, with the last entry being the current, still-open bucket.
+func bucketWindow(now time.Time, granularity string, backfill int, loc *time.Location) []time.Time {
+	now = now.In(loc)
+	width := bucketWidth(granularity)
+
+	var current time.Time
+	if granularity == "hour" {
+		current = now.Truncate(time.Hour)
+	} else {
+		current = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	}
+
+	starts := make([]time.Time, backfill)
+	for i := 0; i < backfill; i++ {
+		if granularity == "hour" {
+			starts[backfill-1-i] = current.Add(-time.Duration(i) * width)
+		} else {
+			// AddDate, not a fixed 24h subtraction: calendar days aren't
+			// all 24h long in zones that observe DST, and a fixed
+			// subtraction would drift the bucket off local midnight.
+			starts[backfill-1-i] = current.AddDate(0, 0, -i)
+		}
+	}
+	return starts
+}
+
+// collectBuckets fills in the reward for every bucket in the window,
+// reusing bucketCache for every bucket except the most recent (open) one.
+func collectBuckets(ctx context.Context, db *sql.DB, job Job, account, granularity string, backfill int, cache *bucketCache) ([]BucketReward, error) {
+	if job.SQLTemplate != "" {
+		return nil, fmt.Errorf("job %s: sql_template is not supported with --backfill>0 (bucketed queries need a [start, end) range that sql_template has no placeholder for)", job.Project)
+	}
+
+	loc, err := time.LoadLocation(job.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("load timezone %q: %w", job.Timezone, err)
+	}
+
+	starts := bucketWindow(time.Now(), granularity, backfill, loc)
+	width := bucketWidth(granularity)
+	out := make([]BucketReward, 0, len(starts))
+
+	for i, bucketStart := range starts {
+		isOpen := i == len(starts)-1
+
+		if !isOpen {
+			if reward, ok := cache.get(job.Project, account, bucketStart); ok {
+				out = append(out, BucketReward{BucketStart: bucketStart, Reward: reward})
+				continue
+			}
+		}
+
+		bucketEnd := bucketStart.Add(width)
+		if granularity != "hour" {
+			// Next local midnight, not start+24h, for the same DST reason
+			// as in bucketWindow.
+			bucketEnd = bucketStart.AddDate(0, 0, 1)
+		}
+		reward, err := queryRewardBucket(ctx, db, job, account, bucketStart, bucketEnd)
+		if err != nil {
+			return nil, err
+		}
+		if !isOpen {
+			cache.set(job.Project, account, bucketStart, reward)
+		}
+		out = append(out, BucketReward{BucketStart: bucketStart, Reward: reward})
+	}
+
+	if len(starts) > 0 {
+		cache.prune(job.Project, account, starts[0])
+	}
+	return out, nil
+}
+
+// bucketGauge builds a per-bucket gauge named after the job's metric, with a
+// bucket_start label carrying the bucket's start time in RFC3339.
+func bucketGauge(metricName, project, account, tz string, b BucketReward) prometheus.Gauge {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: metricName,
+		ConstLabels: prometheus.Labels{
+			"project":      project,
+			"account":      account,
+			"tz":           tz,
+			"bucket_start": b.BucketStart.Format(time.RFC3339),
+		},
+	})
+	gauge.Set(b.Reward)
+	return gauge
+}
+
+// bucketResult is one account's bucket collection outcome, gathered
+// concurrently and applied to the registry serially afterward.
+type bucketResult struct {
+	job     Job
+	account string
+	buckets []BucketReward
+	err     error
+}
+
+// collectBucketsConcurrently runs collectBuckets for every (job, account)
+// pair under queryOpts' concurrency/timeout bounds, recording query health
+// metrics per account along the way.
+func collectBucketsConcurrently(ctx context.Context, db *sql.DB, jobs []Job, granularity string, backfill int, cache *bucketCache, metrics *Metrics, queryOpts QueryOptions) []bucketResult {
+	var resultsMu sync.Mutex
+	var results []bucketResult
+
+	queryAccountsConcurrently(ctx, jobs, queryOpts, func(qctx context.Context, j Job, acc string) {
+		metrics.QueryInflight.Inc()
+		defer metrics.QueryInflight.Dec()
+
+		start := time.Now()
+		buckets, err := collectBuckets(qctx, db, j, acc, granularity, backfill, cache)
+		metrics.observeQuery(j.Project, acc, time.Since(start).Seconds(), err)
+
+		resultsMu.Lock()
+		results = append(results, bucketResult{job: j, account: acc, buckets: buckets, err: err})
+		resultsMu.Unlock()
+	})
+
+	return results
+}
+
+// runPushModeBuckets 与 runPushMode 类似，但每个账户按 --granularity 分桶，
+// 回填最近 --backfill 个窗口，已落定的历史桶通过 bucketCache 跳过重复查询。
+// 与 runPushMode 一样，每个 tick 都会调用 loadJobs 重新加载 --config，
+// 账户的增删会立即反映到下一次 push；ttl 仅用于在账户持续查询失败超过
+// 该时长时额外打一条告警日志——Push（而非 PushAdd）每次都会整体替换同
+// 一 grouping key 下的指标，所以失败账户本来就不会出现在当次 push 里。
+func runPushModeBuckets(ctx context.Context, db *sql.DB, jobs []Job, loadJobs func() ([]Job, error), metrics *Metrics, logger *zap.SugaredLogger, ttl time.Duration, pushgateway, pushJob, instance, granularity string, backfill int, cache *bucketCache, queryOpts QueryOptions, ticker *time.Ticker) {
+	guard := &tickGuard{}
+	var inFlight sync.WaitGroup
+
+	type acctKey struct {
+		project string
+		account string
+	}
+	lastOK := make(map[acctKey]time.Time)
+	var lastOKMu sync.Mutex
+
+	tick := func(jobs []Job) {
+		reg := prometheus.NewRegistry()
+		metrics.Register(reg)
+
+		lastOKMu.Lock()
+		defer lastOKMu.Unlock()
+
+		for _, r := range collectBucketsConcurrently(ctx, db, jobs, granularity, backfill, cache, metrics, queryOpts) {
+			k := acctKey{r.job.Project, r.account}
+			if r.err != nil {
+				logger.Errorw("collectBuckets failed", "project", r.job.Project, "account", r.account, "error", r.err)
+				if ttl > 0 && !lastOK[k].IsZero() && time.Since(lastOK[k]) > ttl {
+					logger.Warnw("account stale beyond ttl, dropping its series", "project", r.job.Project, "account", r.account, "ttl", ttl)
+				}
+				continue
+			}
+			lastOK[k] = time.Now()
+			for _, b := range r.buckets {
+				if err := reg.Register(bucketGauge(r.job.MetricName, r.job.Project, r.account, r.job.Timezone, b)); err != nil {
+					logger.Errorw("register gauge failed", "project", r.job.Project, "account", r.account, "error", err)
+				}
+			}
+		}
+
+		err := push.New(pushgateway, pushJob).
+			Grouping("instance", instance).
+			Gatherer(reg).
+			Push()
+		if err != nil {
+			logger.Errorw("push to Pushgateway failed", "push_status", "error", "error", err)
+			metrics.PushTotal.WithLabelValues("error").Inc()
+		} else {
+			logger.Infow("metrics pushed", "push_status", "ok")
+			metrics.PushTotal.WithLabelValues("ok").Inc()
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Shutting down, waiting for in-flight tick before deleting pushed series")
+			inFlight.Wait()
+			if err := push.New(pushgateway, pushJob).Grouping("instance", instance).Delete(); err != nil {
+				logger.Errorw("delete series from Pushgateway failed", "error", err)
+			}
+			return
+		case <-ticker.C:
+			if !guard.tryStart() {
+				logger.Warn("previous tick still running, skipping this one")
+				continue
+			}
+			reloaded, err := loadJobs()
+			if err != nil {
+				logger.Errorw("reload config failed, keeping previous job list", "error", err)
+				reloaded = jobs
+			}
+			jobs = reloaded
+			inFlight.Add(1)
+			go func(jobs []Job) {
+				defer inFlight.Done()
+				defer guard.finish()
+				tick(jobs)
+			}(jobs)
+		}
+	}
+}
+
+// runPullModeBuckets 与 runPullMode 类似，但暴露按 --granularity 分桶、回填
+// --backfill 个窗口的历史序列，而不是只暴露当日总额。与 runPullMode 一样，
+// 每个 tick 都会调用 loadJobs 重新加载 --config（感知账户的增删），账户
+// 查询持续失败超过 ttl 时会被整体 Unregister，避免其所有桶都冻结在
+// /metrics 上。
+func runPullModeBuckets(ctx context.Context, db *sql.DB, jobs []Job, loadJobs func() ([]Job, error), metrics *Metrics, logger *zap.SugaredLogger, ttl time.Duration, listen, granularity string, backfill int, cache *bucketCache, queryOpts QueryOptions, ticker *time.Ticker) {
+	reg := prometheus.NewRegistry()
+	metrics.Register(reg)
+	guard := &tickGuard{}
+	var inFlight sync.WaitGroup
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+	go func() {
+		logger.Infow("serving /metrics", "listen", listen)
+		if err := http.ListenAndServe(listen, mux); err != nil {
+			logger.Fatalf("metrics server error: %v", err)
+		}
+	}()
+
+	type acctKey struct {
+		project string
+		account string
+	}
+
+	// registered 跟踪当前已注册到 reg 的桶 Gauge：每个 tick 更新已有桶的值、
+	// 注册新出现的桶并注销滑出窗口的桶，因为随着时间推移桶的集合会整体平移。
+	registered := make(map[string]prometheus.Gauge)
+	lastOK := make(map[acctKey]time.Time)
+
+	refresh := func(jobs []Job) {
+		seen := make(map[string]bool)
+
+		for _, r := range collectBucketsConcurrently(ctx, db, jobs, granularity, backfill, cache, metrics, queryOpts) {
+			k := acctKey{r.job.Project, r.account}
+			if r.err != nil {
+				logger.Errorw("collectBuckets failed", "project", r.job.Project, "account", r.account, "error", r.err)
+				if ttl == 0 || lastOK[k].IsZero() || time.Since(lastOK[k]) <= ttl {
+					// 尚未超过 ttl：保留上一次成功查询落下的桶，避免单次
+					// 失败就让 /metrics 上的历史序列闪断。
+					prefix := fmt.Sprintf("%s|%s|", r.job.Project, r.account)
+					for existingKey := range registered {
+						if strings.HasPrefix(existingKey, prefix) {
+							seen[existingKey] = true
+						}
+					}
+					continue
+				}
+				logger.Warnw("account stale beyond ttl, removing its series", "project", r.job.Project, "account", r.account, "ttl", ttl)
+				delete(lastOK, k)
+				continue
+			}
+			lastOK[k] = time.Now()
+			for _, b := range r.buckets {
+				bucketKey := fmt.Sprintf("%s|%s|%s", r.job.Project, r.account, b.BucketStart.Format(time.RFC3339))
+				seen[bucketKey] = true
+				if g, ok := registered[bucketKey]; ok {
+					g.Set(b.Reward)
+					continue
+				}
+				g := bucketGauge(r.job.MetricName, r.job.Project, r.account, r.job.Timezone, b)
+				if err := reg.Register(g); err != nil {
+					logger.Errorw("register gauge failed", "project", r.job.Project, "account", r.account, "error", err)
+					continue
+				}
+				registered[bucketKey] = g
+			}
+		}
+		for k, g := range registered {
+			if !seen[k] {
+				reg.Unregister(g)
+				delete(registered, k)
+			}
+		}
+	}
+
+	refresh(jobs)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Shutting down pull-mode exporter")
+			inFlight.Wait()
+			return
+		case <-ticker.C:
+			if !guard.tryStart() {
+				logger.Warn("previous tick still running, skipping this one")
+				continue
+			}
+			reloaded, err := loadJobs()
+			if err != nil {
+				logger.Errorw("reload config failed, keeping previous job list", "error", err)
+				reloaded = jobs
+			}
+			jobs = reloaded
+			inFlight.Add(1)
+			go func(jobs []Job) {
+				defer inFlight.Done()
+				defer guard.finish()
+				refresh(jobs)
+			}(jobs)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketWindowDay(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	now := time.Date(2026, 7, 28, 15, 30, 0, 0, loc)
+
+	got := bucketWindow(now, "day", 3, loc)
+	want := []time.Time{
+		time.Date(2026, 7, 26, 0, 0, 0, 0, loc),
+		time.Date(2026, 7, 27, 0, 0, 0, 0, loc),
+		time.Date(2026, 7, 28, 0, 0, 0, 0, loc),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("bucket[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBucketWindowHour(t *testing.T) {
+	loc := time.UTC
+	now := time.Date(2026, 7, 28, 15, 45, 12, 0, loc)
+
+	got := bucketWindow(now, "hour", 2, loc)
+	want := []time.Time{
+		time.Date(2026, 7, 28, 14, 0, 0, 0, loc),
+		time.Date(2026, 7, 28, 15, 0, 0, 0, loc),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("bucket[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestBucketWindowCrossesDST exercises the day bucket math across a
+// timezone that observes DST, where naive "subtract 24h" arithmetic would
+// drift off local midnight.
+func TestBucketWindowCrossesDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	// 2026-03-08 is the US spring-forward date.
+	now := time.Date(2026, 3, 9, 12, 0, 0, 0, loc)
+
+	got := bucketWindow(now, "day", 2, loc)
+	for i, b := range got {
+		if b.Hour() != 0 || b.Minute() != 0 {
+			t.Errorf("bucket[%d] = %v, want local midnight", i, b)
+		}
+	}
+	if !got[1].Equal(time.Date(2026, 3, 9, 0, 0, 0, 0, loc)) {
+		t.Errorf("last bucket = %v, want 2026-03-09 local midnight", got[1])
+	}
+}
+
+func TestBucketCacheKeyedByProjectAndAccount(t *testing.T) {
+	cache := newBucketCache()
+	bucketStart := time.Date(2026, 7, 27, 0, 0, 0, 0, time.UTC)
+
+	cache.set("PROJECT_A", "alice", bucketStart, 1.5)
+	cache.set("PROJECT_B", "alice", bucketStart, 9.0)
+
+	got, ok := cache.get("PROJECT_A", "alice", bucketStart)
+	if !ok || got != 1.5 {
+		t.Fatalf("PROJECT_A/alice = %v, %v, want 1.5, true", got, ok)
+	}
+	got, ok = cache.get("PROJECT_B", "alice", bucketStart)
+	if !ok || got != 9.0 {
+		t.Fatalf("PROJECT_B/alice = %v, %v, want 9.0, true", got, ok)
+	}
+}
+
+func TestBucketCachePruneScopedToProjectAccount(t *testing.T) {
+	cache := newBucketCache()
+	old := time.Date(2026, 7, 20, 0, 0, 0, 0, time.UTC)
+	kept := time.Date(2026, 7, 27, 0, 0, 0, 0, time.UTC)
+
+	cache.set("PROJECT_A", "alice", old, 1)
+	cache.set("PROJECT_A", "alice", kept, 2)
+	cache.set("PROJECT_B", "alice", old, 3)
+
+	cache.prune("PROJECT_A", "alice", kept)
+
+	if _, ok := cache.get("PROJECT_A", "alice", old); ok {
+		t.Error("PROJECT_A/alice old bucket should have been pruned")
+	}
+	if _, ok := cache.get("PROJECT_A", "alice", kept); !ok {
+		t.Error("PROJECT_A/alice kept bucket should remain")
+	}
+	if _, ok := cache.get("PROJECT_B", "alice", old); !ok {
+		t.Error("pruning PROJECT_A must not touch PROJECT_B's cache")
+	}
+}
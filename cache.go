@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// bucketCacheKey identifies one project's account's bucket. Keying on
+// account alone would let two jobs that happen to share an account name
+// (e.g. two projects both operating in Asia/Shanghai) silently read and
+// overwrite each other's cached reward.
+type bucketCacheKey struct {
+	project     string
+	account     string
+	bucketStart time.Time
+}
+
+// bucketCache remembers the reward sum already computed for a (project,
+// account, bucket) triple. Every bucket except the most recent one is
+// "final" — its underlying rows can no longer change — so once a bucket
+// has been queried once it never needs to be queried again. This keeps
+// the backfill window from re-running the aggregation query over the
+// whole history on every tick.
+type bucketCache struct {
+	mu      sync.Mutex
+	buckets map[bucketCacheKey]float64
+}
+
+func newBucketCache() *bucketCache {
+	return &bucketCache{buckets: make(map[bucketCacheKey]float64)}
+}
+
+func (c *bucketCache) get(project, account string, bucketStart time.Time) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.buckets[bucketCacheKey{project, account, bucketStart}]
+	return v, ok
+}
+
+func (c *bucketCache) set(project, account string, bucketStart time.Time, reward float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buckets[bucketCacheKey{project, account, bucketStart}] = reward
+}
+
+// prune drops any cached bucket for (project, account) older than
+// oldestKept, bounding memory to roughly the configured --backfill window.
+func (c *bucketCache) prune(project, account string, oldestKept time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.buckets {
+		if k.project == project && k.account == account && k.bucketStart.Before(oldestKept) {
+			delete(c.buckets, k)
+		}
+	}
+}
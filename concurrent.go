@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// QueryOptions bounds how per-account queries run within a single tick.
+type QueryOptions struct {
+	Concurrency int
+	Timeout     time.Duration
+}
+
+// queryAccountsConcurrently runs fn for every (job, account) pair across
+// jobs, with at most opts.Concurrency calls in flight at once. Each call
+// gets its own opts.Timeout-bounded context carved out of ctx. fn is
+// expected to handle/log its own errors; a failing account must not abort
+// the others, so fn never returns an error here.
+func queryAccountsConcurrently(ctx context.Context, jobs []Job, opts QueryOptions, fn func(ctx context.Context, job Job, account string)) {
+	var g errgroup.Group
+	g.SetLimit(opts.Concurrency)
+
+	for _, j := range jobs {
+		j := j
+		for _, acc := range j.Accounts {
+			acc := acc
+			g.Go(func() error {
+				qctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+				defer cancel()
+				fn(qctx, j, acc)
+				return nil
+			})
+		}
+	}
+	_ = g.Wait()
+}
+
+// tickGuard prevents overlapping ticks: if the previous tick's queries
+// haven't finished by the time the next one is due, the new tick is skipped
+// instead of piling concurrent DB load on top of an already-slow one.
+type tickGuard struct {
+	running int32
+}
+
+// tryStart reports whether this call acquired the guard. The caller must
+// call finish exactly once for every tryStart that returns true.
+func (g *tickGuard) tryStart() bool {
+	return atomic.CompareAndSwapInt32(&g.running, 0, 1)
+}
+
+func (g *tickGuard) finish() {
+	atomic.StoreInt32(&g.running, 0)
+}
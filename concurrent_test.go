@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestTickGuardPreventsOverlap(t *testing.T) {
+	guard := &tickGuard{}
+
+	if !guard.tryStart() {
+		t.Fatal("first tryStart should succeed")
+	}
+	if guard.tryStart() {
+		t.Fatal("second tryStart should fail while the first tick is still running")
+	}
+
+	guard.finish()
+	if !guard.tryStart() {
+		t.Fatal("tryStart should succeed again after finish")
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Job describes one per-project daily-reward query to run on every tick.
+// Jobs are declared in the YAML file passed via --config and let a single
+// exporter instance cover several projects and timezones at once.
+type Job struct {
+	Project     string   `yaml:"project"`
+	Timezone    string   `yaml:"timezone"`
+	MetricName  string   `yaml:"metric_name"`
+	Accounts    []string `yaml:"accounts"`
+	SQLTemplate string   `yaml:"sql_template,omitempty"`
+}
+
+// Config is the top-level layout of --config=monitor.yaml.
+type Config struct {
+	Jobs []Job `yaml:"jobs"`
+}
+
+// LoadConfig reads and parses the job list from path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if len(cfg.Jobs) == 0 {
+		return nil, fmt.Errorf("config %s defines no jobs", path)
+	}
+	for i, j := range cfg.Jobs {
+		if j.Project == "" {
+			return nil, fmt.Errorf("config %s: jobs[%d] is missing project", path, i)
+		}
+		if j.Timezone == "" {
+			return nil, fmt.Errorf("config %s: jobs[%d] is missing timezone", path, i)
+		}
+		if j.MetricName == "" {
+			return nil, fmt.Errorf("config %s: jobs[%d] is missing metric_name", path, i)
+		}
+		if len(j.Accounts) == 0 {
+			return nil, fmt.Errorf("config %s: jobs[%d] declares no accounts", path, i)
+		}
+	}
+	return &cfg, nil
+}
+
+// singleJobFromFlags builds the legacy single-project job out of the
+// original --account/--metrics flags, for callers that don't pass --config.
+func singleJobFromFlags(metricsName string, accounts []string) Job {
+	return Job{
+		Project:    "ALEO",
+		Timezone:   "Asia/Shanghai",
+		MetricName: metricsName,
+		Accounts:   accounts,
+	}
+}
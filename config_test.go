@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "monitor.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigValid(t *testing.T) {
+	path := writeConfig(t, `
+jobs:
+  - project: ALEO
+    timezone: Asia/Shanghai
+    metric_name: aleo_daily_reward
+    accounts: [alice, bob]
+  - project: OTHER
+    timezone: UTC
+    metric_name: other_daily_reward
+    accounts: [carol]
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.Jobs) != 2 {
+		t.Fatalf("got %d jobs, want 2", len(cfg.Jobs))
+	}
+	if cfg.Jobs[0].Project != "ALEO" || len(cfg.Jobs[0].Accounts) != 2 {
+		t.Errorf("jobs[0] = %+v, unexpected", cfg.Jobs[0])
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("expected error for missing config file")
+	}
+}
+
+func TestLoadConfigNoJobs(t *testing.T) {
+	path := writeConfig(t, "jobs: []\n")
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("expected error for empty jobs list")
+	}
+}
+
+func TestLoadConfigMissingRequiredField(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+	}{
+		{"missing project", "jobs:\n  - timezone: UTC\n    metric_name: m\n    accounts: [a]\n"},
+		{"missing timezone", "jobs:\n  - project: P\n    metric_name: m\n    accounts: [a]\n"},
+		{"missing metric_name", "jobs:\n  - project: P\n    timezone: UTC\n    accounts: [a]\n"},
+		{"missing accounts", "jobs:\n  - project: P\n    timezone: UTC\n    metric_name: m\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, tc.yaml)
+			if _, err := LoadConfig(path); err == nil {
+				t.Fatalf("expected error for %s", tc.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics bundles the operational instrumentation that sits alongside the
+// per-account reward gauges: query outcomes, push outcomes, query latency
+// and per-account staleness, so operators get SLO-quality visibility
+// (error rate, p95 query latency, staleness) rather than only the reward
+// value itself.
+type Metrics struct {
+	QueryTotal    *prometheus.CounterVec
+	PushTotal     *prometheus.CounterVec
+	QueryDuration *prometheus.HistogramVec
+	LastSuccess   *prometheus.GaugeVec
+	QueryInflight prometheus.Gauge
+}
+
+// NewMetrics constructs the instrumentation. Collectors are not registered
+// yet; call Register on whichever registry ends up serving /metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		QueryTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "reward_query_total",
+			Help: "Total number of daily reward queries, partitioned by result (ok|error).",
+		}, []string{"project", "account", "result"}),
+		PushTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "reward_push_total",
+			Help: "Total number of Pushgateway push attempts, partitioned by result (ok|error).",
+		}, []string{"result"}),
+		QueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "reward_query_duration_seconds",
+			Help:    "Duration of daily reward DB queries in seconds.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"project", "account"}),
+		LastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "reward_last_success_timestamp_seconds",
+			Help: "Unix timestamp of the last successful daily reward query, per account.",
+		}, []string{"project", "account"}),
+		QueryInflight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "reward_query_inflight",
+			Help: "Number of per-account reward queries currently in flight.",
+		}),
+	}
+}
+
+// Register adds every collector in m to reg.
+func (m *Metrics) Register(reg *prometheus.Registry) {
+	reg.MustRegister(m.QueryTotal, m.PushTotal, m.QueryDuration, m.LastSuccess, m.QueryInflight)
+}
+
+// observeQuery records the outcome and latency of a single account query and,
+// on success, stamps its last-success timestamp.
+func (m *Metrics) observeQuery(project, account string, durationSeconds float64, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.QueryTotal.WithLabelValues(project, account, result).Inc()
+	m.QueryDuration.WithLabelValues(project, account).Observe(durationSeconds)
+	if err == nil {
+		m.LastSuccess.WithLabelValues(project, account).SetToCurrentTime()
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// defaultSQLTemplate mirrors the original hard-coded ALEO/Asia-Shanghai query,
+// bound to $1 account, $2 project and $3 timezone so it can serve any job
+// that doesn't supply its own sql_template. project and timezone are bind
+// parameters rather than Sprintf'd into the query text, so a project name
+// or timezone string containing a quote can't break the query.
+const defaultSQLTemplate = `
+        SELECT COALESCE(SUM(reward)/1e6, 0)
+        FROM epoch_distributor
+        WHERE project = $2
+          AND miner_account_id IN (
+              SELECT id FROM miner_account WHERE name = $1
+          )
+          AND DATE(epoch_time AT TIME ZONE $3) = DATE(NOW() AT TIME ZONE $3)
+    `
+
+// queryDailyReward 查询单个 account 在指定 job（project + timezone）下的今日收益。
+// ctx 通常带有 --query-timeout 的超时，防止单个慢查询拖垮整个 tick。
+// 自定义 job.SQLTemplate 沿用历史约定，只接收 $1 account；只有内置的
+// defaultSQLTemplate 额外绑定了 $2 project、$3 timezone。
+func queryDailyReward(ctx context.Context, db *sql.DB, job Job, account string) (float64, error) {
+	var row *sql.Row
+	if job.SQLTemplate != "" {
+		row = db.QueryRowContext(ctx, job.SQLTemplate, account)
+	} else {
+		row = db.QueryRowContext(ctx, defaultSQLTemplate, account, job.Project, job.Timezone)
+	}
+
+	var dailyReward float64
+	if err := row.Scan(&dailyReward); err != nil {
+		return 0, err
+	}
+	return dailyReward, nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// newShutdownContext returns a context that is canceled as soon as the
+// process receives SIGINT or SIGTERM, so the run loops get a chance to
+// clean up (stop pushing, delete their Pushgateway series) before exiting.
+func newShutdownContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+	return ctx
+}